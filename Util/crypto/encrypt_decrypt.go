@@ -11,10 +11,31 @@ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLI
 package encryptutil
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+)
+
+const (
+	// streamChunkSize is the plaintext size sealed into each frame by
+	// EncryptStream/DecryptStream, so encrypting/decrypting a file never
+	// needs to hold more than one chunk of it in memory at a time.
+	streamChunkSize = 64 * 1024 // 64 KiB
+
+	noncePrefixLen = 8
+	counterLen     = 4
+	// lastChunkFlag is OR'd into the big-endian counter for the chunk
+	// EncryptStream knows is the last one, so DecryptStream can refuse to
+	// treat a truncated ciphertext as complete.
+	lastChunkFlag = uint32(1) << 31
 )
 
 // SymmetricEncryption structure
@@ -52,43 +73,226 @@ func (pSymEncrpt *SymmetricEncryption) Decrypt(cipherText []byte, nonce string)
 	return plainText, err
 }
 
-// EncryptFile function encrypts the file passed
+// EncryptFile function encrypts the file passed. It streams the file
+// through EncryptStream in fixed-size chunks and writes the ciphertext
+// straight to a temp file in the same directory rather than reading it
+// whole into memory, so it no longer OOMs (or silently truncates the
+// ciphertext, since gcm.Seal can't safely write into the plaintext buffer
+// it was reusing) on certs/blobs that don't fit comfortably in RAM. The
+// temp file is renamed over filePath only once encryption succeeds, so a
+// failure partway through never leaves filePath half-encrypted (writing
+// filePath in place, like WriteEncryptedPEMFiles does for a fresh
+// destination, isn't an option here since the same path is also the
+// source EncryptStream is still reading from). nonce is bound in as
+// additional authenticated data, so a ciphertext sealed under one nonce
+// can't be swapped in for another file's.
 func (pSymEncrpt *SymmetricEncryption) EncryptFile(filePath string, nonce string) error {
 	if _, err := os.Stat(filePath); err != nil {
 		return err
 	}
-	plainText, err := ioutil.ReadFile(filePath)
+	plainText, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
-	cipherText, err := pSymEncrpt.Encrypt(plainText, nonce)
+	defer plainText.Close()
 
-	err = ioutil.WriteFile(filePath, cipherText, 0777)
+	tmpFile, err := ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".enc-*")
 	if err != nil {
 		return err
 	}
-	return nil
+	tmpPath := tmpFile.Name()
+
+	if err := pSymEncrpt.EncryptStream(plainText, tmpFile, []byte(nonce)); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0777); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, filePath)
 }
 
 // DecryptFile function decrypts the file passed and returns just the decrypted content if overwrite flag is not set.
-// If overwriteFile flag is set, then the file itself is decrypted
+// If overwriteFile flag is set, then the file itself is decrypted. The decrypt work always streams through
+// DecryptStream in fixed-size chunks, same as EncryptFile; the returned []byte still has to hold the whole
+// plaintext, since that's what this function's signature promises callers, but when overwriteFile is set the
+// file write happens as a second destination of that same stream (via io.MultiWriter, renamed into place on
+// success) instead of a separate buffered pass over the plaintext afterward.
 func (pSymEncrpt *SymmetricEncryption) DecryptFile(filePath string, nonce string, overwriteFile bool) ([]byte, error) {
 	if _, err := os.Stat(filePath); err != nil {
 		return nil, err
 	}
-	cipherText, err := ioutil.ReadFile(filePath)
+	cipherText, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	plainText, err := pSymEncrpt.Decrypt(cipherText, nonce)
-	if err != nil {
+	defer cipherText.Close()
+
+	var plainText bytes.Buffer
+	dst := io.Writer(&plainText)
+
+	var tmpFile *os.File
+	var tmpPath string
+	if overwriteFile {
+		tmpFile, err = ioutil.TempFile(filepath.Dir(filePath), filepath.Base(filePath)+".dec-*")
+		if err != nil {
+			return nil, err
+		}
+		tmpPath = tmpFile.Name()
+		dst = io.MultiWriter(&plainText, tmpFile)
+	}
+
+	if err := pSymEncrpt.DecryptStream(cipherText, dst, []byte(nonce)); err != nil {
+		if tmpFile != nil {
+			tmpFile.Close()
+			os.Remove(tmpPath)
+		}
 		return nil, err
 	}
+
 	if overwriteFile {
-		err = ioutil.WriteFile(filePath, plainText, 0777)
-		if err != nil {
+		if err := tmpFile.Close(); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		if err := os.Chmod(tmpPath, 0777); err != nil {
+			os.Remove(tmpPath)
+			return nil, err
+		}
+		if err := os.Rename(tmpPath, filePath); err != nil {
+			os.Remove(tmpPath)
 			return nil, err
 		}
 	}
-	return plainText, err
+	return plainText.Bytes(), nil
+}
+
+// EncryptStream encrypts r in streamChunkSize chunks, writing
+// [8-byte random nonce prefix][chunk frames...] to w. Each frame is
+// [4-byte big-endian length][ciphertext||tag], sealed under a nonce of
+// prefix||counter where counter increments per chunk. The chunk
+// EncryptStream knows is last - even an empty one, for an empty or
+// exact-multiple-of-streamChunkSize input - is sealed with lastChunkFlag set
+// in its counter, so DecryptStream can tell a complete stream from a
+// truncated one.
+func (pSymEncrpt *SymmetricEncryption) EncryptStream(r io.Reader, w io.Writer, aad []byte) error {
+	prefix := make([]byte, noncePrefixLen)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+
+	buf := make([]byte, streamChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(r, buf)
+		last := false
+		switch err {
+		case nil:
+		case io.ErrUnexpectedEOF, io.EOF:
+			last = true
+		default:
+			return err
+		}
+
+		seal := counter
+		if last {
+			seal |= lastChunkFlag
+		}
+		cipherText := pSymEncrpt.gcm.Seal(nil, sealNonce(prefix, seal), buf[:n], aad)
+		if err := writeFrame(w, cipherText); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// DecryptStream reverses EncryptStream, writing the decrypted plaintext to
+// w. It returns an error if the stream ends before a chunk sealed with
+// lastChunkFlag is seen, which is what stops a truncated ciphertext from
+// being accepted as a complete one.
+func (pSymEncrpt *SymmetricEncryption) DecryptStream(r io.Reader, w io.Writer, aad []byte) error {
+	prefix := make([]byte, noncePrefixLen)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return fmt.Errorf("encryptutil: read nonce prefix: %w", err)
+	}
+
+	maxFrameLen := streamChunkSize + pSymEncrpt.gcm.Overhead()
+	for counter := uint32(0); ; counter++ {
+		cipherText, err := readFrame(r, maxFrameLen)
+		if err == io.EOF {
+			return errors.New("encryptutil: truncated ciphertext: no terminal chunk")
+		}
+		if err != nil {
+			return err
+		}
+
+		if plainText, openErr := pSymEncrpt.gcm.Open(nil, sealNonce(prefix, counter), cipherText, aad); openErr == nil {
+			if _, err := w.Write(plainText); err != nil {
+				return err
+			}
+			continue
+		}
+
+		plainText, err := pSymEncrpt.gcm.Open(nil, sealNonce(prefix, counter|lastChunkFlag), cipherText, aad)
+		if err != nil {
+			return fmt.Errorf("encryptutil: decrypt chunk %d: %w", counter, err)
+		}
+		_, err = w.Write(plainText)
+		return err
+	}
+}
+
+// sealNonce builds the 12-byte GCM nonce for a chunk: the stream's random
+// prefix followed by its big-endian counter (with lastChunkFlag already
+// OR'd in by the caller, where applicable).
+func sealNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, 0, noncePrefixLen+counterLen)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	return nonce
+}
+
+func writeFrame(w io.Writer, data []byte) error {
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(data)))
+	if _, err := w.Write(length); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// readFrame reads one [4-byte big-endian length][ciphertext||tag] frame.
+// maxLen bounds the allocation against the length prefix before trusting
+// it - otherwise a corrupted or truncated ciphertext with a bogus length
+// field near the uint32 max forces a multi-gigabyte allocation per frame,
+// exactly the kind of oversized-input OOM EncryptStream/DecryptStream
+// exist to avoid.
+func readFrame(r io.Reader, maxLen int) ([]byte, error) {
+	length := make([]byte, 4)
+	if _, err := io.ReadFull(r, length); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("encryptutil: read frame length: %w", err)
+	}
+	frameLen := binary.BigEndian.Uint32(length)
+	if frameLen > uint32(maxLen) {
+		return nil, fmt.Errorf("encryptutil: frame length %d exceeds max %d", frameLen, maxLen)
+	}
+	data := make([]byte, frameLen)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("encryptutil: read frame payload: %w", err)
+	}
+	return data, nil
 }
@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package encryptutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func newTestEncryption(t *testing.T) *SymmetricEncryption {
+	t.Helper()
+	symEncrypt, err := NewSymmetricEncryption("sixteen byte key")
+	if err != nil {
+		t.Fatalf("NewSymmetricEncryption() error = %v", err)
+	}
+	return symEncrypt
+}
+
+func TestEncryptDecryptStreamRoundTrip(t *testing.T) {
+	cases := map[string]int{
+		"empty":            0,
+		"single_chunk":     1024,
+		"exact_chunk":      streamChunkSize,
+		"multi_chunk":      streamChunkSize*2 + 17,
+		"just_over_a_wall": streamChunkSize + 1,
+	}
+	for name, size := range cases {
+		t.Run(name, func(t *testing.T) {
+			symEncrypt := newTestEncryption(t)
+			plainText := bytes.Repeat([]byte{0xAB}, size)
+			aad := []byte("aad-for-" + name)
+
+			var cipherText bytes.Buffer
+			if err := symEncrypt.EncryptStream(bytes.NewReader(plainText), &cipherText, aad); err != nil {
+				t.Fatalf("EncryptStream() error = %v", err)
+			}
+
+			var decrypted bytes.Buffer
+			if err := symEncrypt.DecryptStream(bytes.NewReader(cipherText.Bytes()), &decrypted, aad); err != nil {
+				t.Fatalf("DecryptStream() error = %v", err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plainText) {
+				t.Fatalf("round trip mismatch: got %d bytes, want %d bytes", decrypted.Len(), len(plainText))
+			}
+		})
+	}
+}
+
+func TestDecryptStreamRejectsTruncatedCiphertext(t *testing.T) {
+	symEncrypt := newTestEncryption(t)
+	plainText := bytes.Repeat([]byte{0x11}, streamChunkSize*2+5)
+
+	var cipherText bytes.Buffer
+	if err := symEncrypt.EncryptStream(bytes.NewReader(plainText), &cipherText, nil); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+
+	// Drop the terminal frame so the ciphertext looks like it stopped
+	// mid-stream instead of ending cleanly. The terminal frame carries the
+	// last 5 bytes of plaintext: a 4-byte length, then 5 bytes of
+	// ciphertext plus the GCM tag.
+	terminalFrameLen := 4 + 5 + symEncrypt.gcm.Overhead()
+	truncated := cipherText.Bytes()[:cipherText.Len()-terminalFrameLen]
+
+	var decrypted bytes.Buffer
+	if err := symEncrypt.DecryptStream(bytes.NewReader(truncated), &decrypted, nil); err == nil {
+		t.Fatal("DecryptStream() error = nil, want an error for a truncated stream")
+	}
+}
+
+func TestDecryptStreamRejectsOversizedFrameLength(t *testing.T) {
+	symEncrypt := newTestEncryption(t)
+
+	// A well-formed nonce prefix followed by a frame claiming a
+	// ~4GiB payload. DecryptStream must reject this from the length
+	// prefix alone, without ever attempting the allocation.
+	var cipherText bytes.Buffer
+	cipherText.Write(make([]byte, noncePrefixLen))
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, 0xFFFFFFF0)
+	cipherText.Write(length)
+
+	var decrypted bytes.Buffer
+	err := symEncrypt.DecryptStream(bytes.NewReader(cipherText.Bytes()), &decrypted, nil)
+	if err == nil {
+		t.Fatal("DecryptStream() error = nil, want an error for an oversized frame length")
+	}
+}
+
+func TestDecryptStreamRejectsWrongAAD(t *testing.T) {
+	symEncrypt := newTestEncryption(t)
+	plainText := []byte("secret payload")
+
+	var cipherText bytes.Buffer
+	if err := symEncrypt.EncryptStream(bytes.NewReader(plainText), &cipherText, []byte("sealed-aad")); err != nil {
+		t.Fatalf("EncryptStream() error = %v", err)
+	}
+
+	var decrypted bytes.Buffer
+	err := symEncrypt.DecryptStream(bytes.NewReader(cipherText.Bytes()), &decrypted, []byte("wrong-aad"))
+	if err == nil {
+		t.Fatal("DecryptStream() error = nil, want an error for mismatched AAD")
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package nativetpm implements secretstore.SecretStore directly against
+// /dev/tpmrm0 via go-tpm/go-tpm-tools, with no dependency on the tpm2_*
+// command line binaries. Each secret is sealed to the platform's storage
+// root key and the sealed blob is persisted as a file under baseDir, since
+// the kernel resource manager does not keep objects resident across boots.
+package nativetpm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-tpm-tools/client"
+	tpmpb "github.com/google/go-tpm-tools/proto/tpm"
+	"github.com/google/go-tpm/tpm2"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultDevice  = "/dev/tpmrm0"
+	defaultBaseDir = "/IEI/tpm_secret/native/"
+)
+
+// Driver seals/unseals secrets against the TPM's storage root key using
+// go-tpm-tools, persisting the resulting sealed blobs as files.
+type Driver struct {
+	device  string
+	baseDir string
+}
+
+// New constructs a native TPM driver. cfg["device"] overrides the default
+// TPM character device (/dev/tpmrm0) and cfg["base_dir"] overrides where
+// sealed blobs are persisted (/IEI/tpm_secret/native/).
+func New(cfg map[string]string) (*Driver, error) {
+	device := defaultDevice
+	if v := cfg["device"]; v != "" {
+		device = v
+	}
+	baseDir := defaultBaseDir
+	if v := cfg["base_dir"]; v != "" {
+		baseDir = v
+	}
+	return &Driver{device: device, baseDir: baseDir}, nil
+}
+
+func (d *Driver) blobPath(name string) string {
+	return filepath.Join(d.baseDir, name+".sealed")
+}
+
+// Seal implements secretstore.SecretStore.
+func (d *Driver) Seal(name string, secret []byte) error {
+	rwc, err := tpm2.OpenTPM(d.device)
+	if err != nil {
+		return fmt.Errorf("nativetpm: open %s: %w", d.device, err)
+	}
+	defer rwc.Close()
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		return fmt.Errorf("nativetpm: load storage root key: %w", err)
+	}
+	defer srk.Close()
+
+	sealed, err := srk.Seal(secret, client.SealOpts{})
+	if err != nil {
+		return fmt.Errorf("nativetpm: seal %s: %w", name, err)
+	}
+	// srk.Seal returns a *tpmpb.SealedBytes proto message, not a raw blob,
+	// so it has to be marshaled before it can be written out as a file.
+	data, err := proto.Marshal(sealed)
+	if err != nil {
+		return fmt.Errorf("nativetpm: marshal sealed blob for %s: %w", name, err)
+	}
+	if err := os.MkdirAll(d.baseDir, 0700); err != nil {
+		return fmt.Errorf("nativetpm: create %s: %w", d.baseDir, err)
+	}
+	return ioutil.WriteFile(d.blobPath(name), data, 0600)
+}
+
+// Unseal implements secretstore.SecretStore.
+func (d *Driver) Unseal(name string) ([]byte, error) {
+	data, err := ioutil.ReadFile(d.blobPath(name))
+	if err != nil {
+		return nil, fmt.Errorf("nativetpm: read sealed blob %s: %w", name, err)
+	}
+	var sealed tpmpb.SealedBytes
+	if err := proto.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("nativetpm: unmarshal sealed blob %s: %w", name, err)
+	}
+
+	rwc, err := tpm2.OpenTPM(d.device)
+	if err != nil {
+		return nil, fmt.Errorf("nativetpm: open %s: %w", d.device, err)
+	}
+	defer rwc.Close()
+
+	srk, err := client.StorageRootKeyRSA(rwc)
+	if err != nil {
+		return nil, fmt.Errorf("nativetpm: load storage root key: %w", err)
+	}
+	defer srk.Close()
+
+	secret, err := srk.Unseal(&sealed, client.UnsealOpts{})
+	if err != nil {
+		return nil, fmt.Errorf("nativetpm: unseal %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// Delete implements secretstore.SecretStore.
+func (d *Driver) Delete(name string) error {
+	if err := os.Remove(d.blobPath(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("nativetpm: delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// List implements secretstore.SecretStore.
+func (d *Driver) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(d.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("nativetpm: list %s: %w", d.baseDir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sealed" {
+			names = append(names, entry.Name()[:len(entry.Name())-len(".sealed")])
+		}
+	}
+	return names, nil
+}
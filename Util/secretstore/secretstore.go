@@ -0,0 +1,78 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package secretstore defines the pluggable sealed-secret backend used by
+// video-common and selects a concrete driver (shell-based TPM, native TPM,
+// HashiCorp Vault) from config.
+package secretstore
+
+import (
+	"errors"
+	"fmt"
+
+	"IEdgeInsights/Util/secretstore/nativetpm"
+	"IEdgeInsights/Util/secretstore/shelltpm"
+	"IEdgeInsights/Util/secretstore/vaultstore"
+)
+
+// SecretStore seals, unseals, deletes and enumerates named secrets in a
+// backend-specific sealed store. Implementations must make Unseal fail for
+// any name that was never Sealed (or has since been Deleted).
+type SecretStore interface {
+	// Seal encrypts and persists secret under name, overwriting any
+	// existing secret with the same name.
+	Seal(name string, secret []byte) error
+	// Unseal decrypts and returns the secret previously stored under name.
+	Unseal(name string) ([]byte, error)
+	// Delete removes the secret stored under name. Deleting a name that
+	// does not exist is not an error.
+	Delete(name string) error
+	// List returns the names of all secrets currently held in the store.
+	List() ([]string, error)
+}
+
+// Driver identifies a SecretStore implementation that New can build.
+type Driver string
+
+const (
+	// DriverShellTPM drives the tpm2-tools command line binaries against
+	// the platform TPM. It is the original driver and requires tpm2_* and
+	// openssl to be on PATH.
+	DriverShellTPM Driver = "shell_tpm"
+	// DriverNativeTPM talks to /dev/tpmrm0 directly via go-tpm/go-tpm-tools,
+	// without shelling out to tpm2_* binaries.
+	DriverNativeTPM Driver = "native_tpm"
+	// DriverVault seals secrets into a HashiCorp Vault KV-v2 mount.
+	DriverVault Driver = "vault"
+)
+
+// ErrTPMUnavailable is wrapped by tpmutil's WriteToTpm/ReadFromTpm around
+// any Seal/Unseal failure of the selected driver - a missing tpm2_*/openssl
+// binary, a closed /dev/tpmrm0, a PCR policy that no longer matches, but
+// equally an I/O error on the scratch/blob directory or a Vault error.
+// Callers can check for it with errors.Is. The drivers themselves do not
+// return it, and Delete/List failures are not wrapped with it.
+var ErrTPMUnavailable = errors.New("secretstore: TPM unavailable")
+
+// New constructs the SecretStore selected by driver. cfg carries
+// driver-specific settings, e.g. "base_dir"/"device" for the TPM drivers or
+// "address"/"mount"/"role" for the Vault driver.
+func New(driver Driver, cfg map[string]string) (SecretStore, error) {
+	switch driver {
+	case DriverShellTPM:
+		return shelltpm.New(cfg), nil
+	case DriverNativeTPM:
+		return nativetpm.New(cfg)
+	case DriverVault:
+		return vaultstore.New(cfg)
+	default:
+		return nil, fmt.Errorf("secretstore: unsupported driver %q", driver)
+	}
+}
@@ -0,0 +1,335 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package shelltpm implements secretstore.SecretStore by shelling out to the
+// tpm2-tools command line binaries (tpm2_*) and openssl. This is the
+// original TPM driver carried over from tpmutil, with each name given its
+// own scratch subdirectory and persistent handle, and with command failures
+// returned as errors.
+package shelltpm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/golang/glog"
+)
+
+// ErrUnavailable wraps any failure from the underlying tpm2-tools/openssl
+// commands (missing binary, closed TPM, stale PCR policy, etc). The
+// original error text - including captured stderr - is formatted into the
+// returned error's message with %v; it is not itself wrapped, so
+// errors.Unwrap on the returned error yields ErrUnavailable.
+var ErrUnavailable = errors.New("shelltpm: TPM unavailable")
+
+const (
+	defaultBaseDir = "/IEI/tpm_secret/"
+	// tpmHandleBase anchors the owner-persistent handle range this driver
+	// carves per-name handles out of (0x81010000-0x8101fffe); a single
+	// fixed handle would make every name share one slot, so Seal("b", ...)
+	// would evict whatever Seal("a", ...) left there.
+	tpmHandleBase = 0x81010000
+	pcrBank       = "sha256:"
+	pcrIndex      = "7,8,9"
+)
+
+// Driver drives tpm2-tools to seal/unseal secrets under a PCR policy
+// authorized by a per-secret signing key. Each secret gets its own
+// scratch subdirectory under baseDir, named after it.
+type Driver struct {
+	baseDir string
+}
+
+// New constructs a shell-based TPM driver. cfg["base_dir"] overrides the
+// default scratch directory (/IEI/tpm_secret/) used for tpm2_* intermediate
+// artifacts.
+func New(cfg map[string]string) *Driver {
+	baseDir := defaultBaseDir
+	if v := cfg["base_dir"]; v != "" {
+		baseDir = v
+	}
+	return &Driver{baseDir: baseDir}
+}
+
+func (d *Driver) outDir(name string) string {
+	return filepath.Join(d.baseDir, name) + "/"
+}
+
+// persistentHandle derives name's own TPM persistent handle from the FNV-1a
+// hash of name modulo 0xfffe, so distinct names no longer all share one
+// slot the way the old single fixed tpmAddr constant did. Two names whose
+// hashes are equal modulo 0xfffe still map to the same handle.
+func persistentHandle(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	offset := h.Sum32() % 0xfffe
+	return fmt.Sprintf("0x%08x", tpmHandleBase+offset+1)
+}
+
+// execCmd - Executes command in the shell, returning stdout. On a non-zero
+// exit it returns the captured stderr wrapped in the error instead of just
+// logging and discarding it, so callers can report the real root cause
+// instead of a generic "Sealing Failed".
+func execCmd(tpmCmd string, args []string) ([]byte, error) {
+	var sout, serr bytes.Buffer
+	cmd := exec.Command(tpmCmd, args...)
+	cmd.Stdout = &sout
+	cmd.Stderr = &serr
+	if err := cmd.Run(); err != nil {
+		glog.Errorln("Error is ", serr.String())
+		return nil, fmt.Errorf("%s %v: %v: %s", tpmCmd, args, err, serr.String())
+	}
+	return sout.Bytes(), nil
+}
+
+func clearPersistentMem(handle string) error {
+	var evicArgs = []string{"-a", "o", "-c", handle, "-p", handle}
+	if _, err := execCmd("tpm2_flushcontext", []string{"-t"}); err != nil {
+		return err
+	}
+	_, err := execCmd("tpm2_evictcontrol", evicArgs)
+	return err
+}
+
+// Creates PCR policy over the pcrBank PCRs listed in pcrIndex
+func createPcrPolicy(outDir string) error {
+	var authsessArgs = []string{"-S", outDir + "session.ctx"}
+	var policypcrArgs = []string{"-S", outDir + "session.ctx", "-L", pcrBank + pcrIndex, "-f", outDir + "pcr.policy"}
+
+	if _, err := execCmd("tpm2_startauthsession", authsessArgs); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_policypcr", policypcrArgs); err != nil {
+		return err
+	}
+	_, err := execCmd("tpm2_flushcontext", authsessArgs)
+	return err
+}
+
+// Creates Pub Priv key Pairs..
+func createPubPrivKeyPair(outDir string) error {
+	var genArgs = []string{"genrsa", "-out", outDir + "signing.priv.pem"}
+	var rsaArgs = []string{"rsa", "-in", outDir + "signing.priv.pem",
+		"-out", outDir + "signing.pub.pem", "-pubout"}
+
+	if _, err := execCmd("openssl", genArgs); err != nil {
+		return err
+	}
+	_, err := execCmd("openssl", rsaArgs)
+	return err
+}
+
+func loadPubKeyintoTpm(outDir string) error {
+	var loadArgs = []string{"-G", "rsa", "-a", "o", "-u", outDir + "signing.pub.pem",
+		"-o", outDir + "signing.key.ctx", "-n",
+		outDir + "signing.key.name"}
+
+	_, err := execCmd("tpm2_loadexternal", loadArgs)
+	return err
+}
+
+// Authorize PCR policy with the Public key
+func authorize(outDir string) error {
+	var sessArgs = []string{"-S", outDir + "session.ctx"}
+	var authArgs = []string{"-S", outDir + "session.ctx", "-o", outDir + "authorized.policy", "-n", outDir + "signing.key.name", "-f", outDir + "pcr.policy"}
+
+	if err := flushTpmcontext(); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_startauthsession", sessArgs); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_policyauthorize", authArgs); err != nil {
+		return err
+	}
+	_, err := execCmd("tpm2_flushcontext", sessArgs)
+	return err
+}
+
+// Flush the context
+func flushTpmcontext() error {
+	if _, err := execCmd("tpm2_flushcontext", []string{"-t"}); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_flushcontext", []string{"-s"}); err != nil {
+		return err
+	}
+	_, err := execCmd("tpm2_flushcontext", []string{"-l"})
+	return err
+}
+
+// Seal the secret
+func sealSecret(outDir string, secret []byte, handle string) error {
+	var primArgs = []string{"-V", "-a", "o", "-g", "sha256", "-G", "rsa", "-o", outDir + "prim.ctx"}
+	var evicArgs = []string{"-a", "o", "-c", outDir + "key.obj.ctx", "-p", handle}
+	var createArgs = []string{"-C", outDir + "prim.ctx", "-g", "sha256", "-u",
+		outDir + "key.obj.pub", "-r", outDir + "key.obj.priv",
+		"-I", string(secret), "-L", outDir + "authorized.policy"}
+
+	var loadArgs = []string{"-C", outDir + "prim.ctx", "-u", outDir + "key.obj.pub",
+		"-r", outDir + "key.obj.priv", "-n", outDir + "key.obj.name",
+		"-o", outDir + "key.obj.ctx"}
+
+	if err := flushTpmcontext(); err != nil {
+		return err
+	}
+	glog.Infof("TPM Sealing:: Started ")
+	if _, err := execCmd("tpm2_createprimary", primArgs); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_create", createArgs); err != nil {
+		return fmt.Errorf("TPM Sealing:: Creating Object Failed: %w", err)
+	}
+	if err := flushTpmcontext(); err != nil {
+		return err
+	}
+	if _, err := execCmd("tpm2_load", loadArgs); err != nil {
+		return fmt.Errorf("TPM Sealing:: Loading Object Failed: %w", err)
+	}
+	_, err := execCmd("tpm2_evictcontrol", evicArgs)
+	return err
+}
+
+// Creates Signature from the Priv Key
+func createSignature(outDir string) error {
+	var sigArgs = []string{"dgst", "-sign", outDir + "signing.priv.pem", "-out", outDir + "pcr.signature", outDir + "pcr.policy"}
+	_, err := execCmd("openssl", sigArgs)
+	return err
+}
+
+// Invokes all the helpers functions from sealVault
+func sealVault(outDir string, secret []byte, handle string) error {
+	if err := os.MkdirAll(outDir, 0700); err != nil {
+		return fmt.Errorf("TPM Sealing:: failed to create scratch dir %v: %w", outDir, err)
+	}
+	if err := createPcrPolicy(outDir); err != nil {
+		return err
+	}
+	if err := createPubPrivKeyPair(outDir); err != nil {
+		return err
+	}
+	if err := loadPubKeyintoTpm(outDir); err != nil {
+		return err
+	}
+	if err := authorize(outDir); err != nil {
+		return err
+	}
+	if err := sealSecret(outDir, secret, handle); err != nil {
+		return err
+	}
+	return createSignature(outDir)
+}
+
+// API for unsealing the vault from TPM
+func unsealVault(outDir string, handle string) ([]byte, error) {
+	var sigArgs = []string{"-t", outDir + "verification.tkt",
+		"-c", outDir + "signing.key.ctx", "-G", "sha256",
+		"-m", outDir + "pcr.policy", "-s", outDir + "pcr.signature", "-f", "rsassa"}
+	var sessArgs = []string{"-a", "-S", outDir + "session.ctx"}
+	var policyArgs = []string{"-S", outDir + "session.ctx", "-L", pcrBank + pcrIndex}
+	var authArgs = []string{"-S", outDir + "session.ctx",
+		"-o", outDir + "authorized.policy", "-f", outDir + "pcr.policy",
+		"-n", outDir + "signing.key.name", "-t", outDir + "verification.tkt"}
+	var unsealArgs = []string{"-c", handle, "-p", "session:" + outDir + "session.ctx"}
+
+	glog.Infof("TPM UnSealing:: Started.... ")
+	if err := flushTpmcontext(); err != nil {
+		return nil, err
+	}
+	if err := loadPubKeyintoTpm(outDir); err != nil {
+		return nil, err
+	}
+	if _, err := execCmd("tpm2_verifysignature", sigArgs); err != nil {
+		return nil, fmt.Errorf("TPM UnSealing:: failed to verify signature: %w", err)
+	}
+	glog.Infof("TPM UnSealing:: Verification Ticket Generated... ")
+	if _, err := execCmd("tpm2_startauthsession", sessArgs); err != nil {
+		return nil, err
+	}
+	if _, err := execCmd("tpm2_policypcr", policyArgs); err != nil {
+		return nil, err
+	}
+	if _, err := execCmd("tpm2_policyauthorize", authArgs); err != nil {
+		return nil, fmt.Errorf("TPM UnSealing:: policy authorization failure: %w", err)
+	}
+	glog.Infof("TPM UnSealing:: Policy Authorization Success ")
+	secret, err := execCmd("tpm2_unseal", unsealArgs)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := execCmd("tpm2_flushcontext", []string{"-t"}); err != nil {
+		return nil, err
+	}
+	glog.Infof("TPM UnSealing:: Completed ")
+	return secret, nil
+}
+
+// Seal implements secretstore.SecretStore.
+func (d *Driver) Seal(name string, secret []byte) error {
+	handle := persistentHandle(name)
+	// Evicting a handle that holds nothing fails, which is the normal case
+	// the first time a name is sealed, so a failure here is only logged.
+	if err := clearPersistentMem(handle); err != nil {
+		glog.Infof("TPM Sealing:: nothing evicted from %s: %v", handle, err)
+	}
+	if err := sealVault(d.outDir(name), secret, handle); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	glog.Infof("TPM Sealing:: Completed Successfully... ")
+	return nil
+}
+
+// Unseal implements secretstore.SecretStore.
+func (d *Driver) Unseal(name string) ([]byte, error) {
+	secret, err := unsealVault(d.outDir(name), persistentHandle(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return secret, nil
+}
+
+// Delete implements secretstore.SecretStore.
+func (d *Driver) Delete(name string) error {
+	// A name that was never sealed has no scratch directory.
+	if _, err := os.Stat(d.outDir(name)); os.IsNotExist(err) {
+		return nil
+	}
+	if err := clearPersistentMem(persistentHandle(name)); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err := os.RemoveAll(d.outDir(name)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// List implements secretstore.SecretStore.
+func (d *Driver) List() ([]string, error) {
+	entries, err := ioutil.ReadDir(d.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
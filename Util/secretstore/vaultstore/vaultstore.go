@@ -0,0 +1,147 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package vaultstore implements secretstore.SecretStore against a
+// HashiCorp Vault KV-v2 mount, for deployments that don't have a discrete
+// TPM chip to seal secrets into.
+package vaultstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	approle "github.com/hashicorp/vault/api/auth/approle"
+	kubernetes "github.com/hashicorp/vault/api/auth/kubernetes"
+)
+
+const (
+	defaultMount = "secret"
+	secretField  = "value"
+)
+
+// Driver seals/unseals secrets as base64 values under a single field of a
+// KV-v2 secret, one Vault secret per name.
+type Driver struct {
+	client *vaultapi.Client
+	mount  string
+}
+
+// New constructs a Vault driver from cfg:
+//   - "address": Vault server address (required)
+//   - "mount": KV-v2 mount path (defaults to "secret")
+//   - "auth_method": "approle" or "kubernetes" (required)
+//   - "role_id"/"secret_id": AppRole credentials, for auth_method "approle"
+//   - "role": Vault role name, for auth_method "kubernetes"
+//   - "sa_token_path": path to the service account JWT, for auth_method
+//     "kubernetes" (defaults to the in-cluster projected token path)
+func New(cfg map[string]string) (*Driver, error) {
+	address := cfg["address"]
+	if address == "" {
+		return nil, fmt.Errorf("vaultstore: \"address\" is required")
+	}
+
+	client, err := vaultapi.NewClient(&vaultapi.Config{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("vaultstore: new client: %w", err)
+	}
+
+	auth, err := newAuthMethod(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := client.Auth().Login(context.Background(), auth); err != nil {
+		return nil, fmt.Errorf("vaultstore: login: %w", err)
+	}
+
+	mount := defaultMount
+	if v := cfg["mount"]; v != "" {
+		mount = v
+	}
+
+	return &Driver{client: client, mount: mount}, nil
+}
+
+func newAuthMethod(cfg map[string]string) (vaultapi.AuthMethod, error) {
+	switch cfg["auth_method"] {
+	case "approle":
+		secretID := &approle.SecretID{FromString: cfg["secret_id"]}
+		return approle.NewAppRoleAuth(cfg["role_id"], secretID)
+	case "kubernetes":
+		var opts []kubernetes.LoginOption
+		if v := cfg["sa_token_path"]; v != "" {
+			opts = append(opts, kubernetes.WithServiceAccountTokenPath(v))
+		}
+		return kubernetes.NewKubernetesAuth(cfg["role"], opts...)
+	default:
+		return nil, fmt.Errorf("vaultstore: unsupported auth_method %q", cfg["auth_method"])
+	}
+}
+
+// Seal implements secretstore.SecretStore.
+func (d *Driver) Seal(name string, secret []byte) error {
+	data := map[string]interface{}{
+		secretField: base64.StdEncoding.EncodeToString(secret),
+	}
+	_, err := d.client.KVv2(d.mount).Put(context.Background(), name, data)
+	if err != nil {
+		return fmt.Errorf("vaultstore: seal %s: %w", name, err)
+	}
+	return nil
+}
+
+// Unseal implements secretstore.SecretStore.
+func (d *Driver) Unseal(name string) ([]byte, error) {
+	kv, err := d.client.KVv2(d.mount).Get(context.Background(), name)
+	if err != nil {
+		return nil, fmt.Errorf("vaultstore: unseal %s: %w", name, err)
+	}
+	encoded, ok := kv.Data[secretField].(string)
+	if !ok {
+		return nil, fmt.Errorf("vaultstore: secret %s missing field %q", name, secretField)
+	}
+	secret, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("vaultstore: decode %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// Delete implements secretstore.SecretStore.
+func (d *Driver) Delete(name string) error {
+	if err := d.client.KVv2(d.mount).DeleteMetadata(context.Background(), name); err != nil {
+		return fmt.Errorf("vaultstore: delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// List implements secretstore.SecretStore.
+func (d *Driver) List() ([]string, error) {
+	secret, err := d.client.Logical().List(fmt.Sprintf("%s/metadata", d.mount))
+	if err != nil {
+		return nil, fmt.Errorf("vaultstore: list: %w", err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+	keys, ok := secret.Data["keys"].([]interface{})
+	if !ok {
+		return nil, nil
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if name, ok := key.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -12,6 +12,7 @@ package util
 
 import (
 	cryptoUtil "IEdgeInsights/Util/crypto"
+	"bytes"
 	"io/ioutil"
 	"net"
 	"os"
@@ -83,8 +84,13 @@ func WriteEncryptedPEMFiles(fileList []string, Certs map[string]interface{}) err
 	for _, filePath := range fileList {
 		fileName := filepath.Base(filePath)
 		if data, ok := Certs[fileName].([]byte); ok {
-			cipherText, err := symEncrypt.Encrypt(data, nonce)
-			err = ioutil.WriteFile(filePath, cipherText, 0777)
+			outFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0777)
+			if err != nil {
+				glog.Errorf("Failed to open file for writing: %v, error: %v", filePath, err)
+				return err
+			}
+			err = symEncrypt.EncryptStream(bytes.NewReader(data), outFile, []byte(nonce))
+			outFile.Close()
 			if err != nil {
 				glog.Errorf("Failed to encrypt file: %v, error: %v", filePath, err)
 				return err
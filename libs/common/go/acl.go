@@ -0,0 +1,46 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"IEdgeInsights/libs/messagebus/acl"
+)
+
+// GetMessageBusConfigWithACL builds on GetMessageBusConfig, then - for a
+// "pub" or "server" topic in prod mode - overwrites its "allowed_clients"
+// entry with the per-topic allow-list aclMgr.AllowedClients resolves from
+// policy, instead of leaving every topic in the app authorized identically
+// off the "Clients" env var. A topic that policy gives no allow-list for
+// keeps the "Clients" env var entry. aclMgr and policy may be nil, in which case
+// this is exactly GetMessageBusConfig; callers get policy from aclMgr.Load
+// or off the channel aclMgr.Reload returns.
+func GetMessageBusConfigWithACL(topic string, topicType string, devMode bool, cfgMgrConfig map[string]string, aclMgr *acl.Manager, policy *acl.Policy) (map[string]interface{}, error) {
+	messageBusConfig, err := GetMessageBusConfig(topic, topicType, devMode, cfgMgrConfig)
+	if err != nil {
+		return nil, err
+	}
+	if aclMgr == nil || policy == nil || devMode {
+		return messageBusConfig, nil
+	}
+
+	var role acl.Role
+	switch strings.ToLower(topicType) {
+	case "pub":
+		role = acl.RolePublisher
+	case "server":
+		role = acl.RoleServer
+	default:
+		return messageBusConfig, nil
+	}
+
+	allowedClients, err := aclMgr.AllowedClients(context.Background(), policy, strings.TrimSpace(topic), role)
+	if err != nil {
+		return nil, fmt.Errorf("%w: acl allowed_clients for %s: %v", ErrConfigMissing, topic, err)
+	}
+	if allowedClients != nil {
+		messageBusConfig["allowed_clients"] = allowedClients
+	}
+	return messageBusConfig, nil
+}
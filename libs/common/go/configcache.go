@@ -0,0 +1,123 @@
+package util
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultConfigCacheTTL is the TTL applied by NewConfigCache callers that
+// don't have a more specific requirement (GetMessageBusConfig's own
+// default).
+const DefaultConfigCacheTTL = 30 * time.Second
+
+// configGetter is satisfied by configmgr.ConfigManager's GetConfig method.
+// It's declared locally (rather than importing the concrete type) so
+// ConfigCache can wrap whatever ConfigManager implementation GetConfig is
+// called against without taking on a hard dependency of its own.
+type configGetter interface {
+	GetConfig(key string) (string, error)
+}
+
+// CacheStats reports ConfigCache hit/miss/eviction counts so operators can
+// see whether the cache is actually saving etcd round-trips.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// ConfigCache fronts a configmgr.ConfigManager with a TTL cache, so a
+// publisher with N subscribers doesn't reissue the same N+1 etcd reads on
+// every GetMessageBusConfig call and reconnect. Safe for concurrent use.
+type ConfigCache struct {
+	mu      sync.RWMutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	stats   CacheStats
+}
+
+// NewConfigCache returns a ConfigCache that keeps entries for ttl. A ttl of
+// zero disables caching - every Get is a pass-through miss.
+func NewConfigCache(ttl time.Duration) *ConfigCache {
+	return &ConfigCache{
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the cached value for key if present and unexpired, otherwise
+// fetches it from cli, caches it, and returns it.
+func (c *ConfigCache) Get(cli configGetter, key string) (string, error) {
+	if c.ttl <= 0 {
+		c.mu.Lock()
+		c.stats.Misses++
+		c.mu.Unlock()
+		return cli.GetConfig(key)
+	}
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+
+	value, err := cli.GetConfig(key)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return value, nil
+}
+
+func (c *ConfigCache) lookup(key string) (string, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		c.mu.Lock()
+		c.stats.Misses++
+		c.mu.Unlock()
+		return "", false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.stats.Evictions++
+		c.stats.Misses++
+		c.mu.Unlock()
+		return "", false
+	}
+
+	c.mu.Lock()
+	c.stats.Hits++
+	c.mu.Unlock()
+	return entry.value, true
+}
+
+// InvalidateKey evicts every cached key with the given prefix, letting the
+// acl reload/watch path punch a hole on rotation instead of waiting out the
+// TTL.
+func (c *ConfigCache) InvalidateKey(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+			c.stats.Evictions++
+		}
+	}
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters.
+func (c *ConfigCache) Stats() CacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stats
+}
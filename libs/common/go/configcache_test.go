@@ -0,0 +1,101 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// countingGetter satisfies configGetter, counting calls per key so tests
+// can assert whether ConfigCache actually avoided a round-trip.
+type countingGetter struct {
+	calls map[string]int
+}
+
+func newCountingGetter() *countingGetter {
+	return &countingGetter{calls: make(map[string]int)}
+}
+
+func (g *countingGetter) GetConfig(key string) (string, error) {
+	g.calls[key]++
+	return fmt.Sprintf("%s-%d", key, g.calls[key]), nil
+}
+
+func TestConfigCacheHitsUntilTTLExpires(t *testing.T) {
+	cli := newCountingGetter()
+	cache := NewConfigCache(time.Hour)
+
+	first, err := cache.Get(cli, "/Publickeys/a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	second, err := cache.Get(cli, "/Publickeys/a")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if first != second {
+		t.Fatalf("Get() returned %q then %q, want a cached hit to return the same value", first, second)
+	}
+	if got := cli.calls["/Publickeys/a"]; got != 1 {
+		t.Fatalf("underlying GetConfig called %d times, want 1", got)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats() = %+v, want 1 hit and 1 miss", stats)
+	}
+}
+
+func TestConfigCacheExpiresAfterTTL(t *testing.T) {
+	cli := newCountingGetter()
+	cache := NewConfigCache(time.Millisecond)
+
+	if _, err := cache.Get(cli, "/Publickeys/a"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.Get(cli, "/Publickeys/a"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if got := cli.calls["/Publickeys/a"]; got != 2 {
+		t.Fatalf("underlying GetConfig called %d times after TTL expiry, want 2", got)
+	}
+	if got := cache.Stats().Evictions; got != 1 {
+		t.Fatalf("Stats().Evictions = %d, want 1", got)
+	}
+}
+
+func TestConfigCacheInvalidateKeyEvictsByPrefix(t *testing.T) {
+	cli := newCountingGetter()
+	cache := NewConfigCache(time.Hour)
+
+	if _, err := cache.Get(cli, "/Publickeys/a"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := cache.Get(cli, "/Publickeys/b"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := cache.Get(cli, "/other/app/private_key"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	// This is the call acl.Manager.Reload makes on every policy rotation,
+	// so a name added or removed from the allow-list is picked up on the
+	// next lookup instead of waiting out the TTL.
+	cache.InvalidateKey("/Publickeys/")
+
+	if _, err := cache.Get(cli, "/Publickeys/a"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := cache.Get(cli, "/other/app/private_key"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if got := cli.calls["/Publickeys/a"]; got != 2 {
+		t.Fatalf("underlying GetConfig called %d times for /Publickeys/a after InvalidateKey, want 2", got)
+	}
+	if got := cli.calls["/other/app/private_key"]; got != 1 {
+		t.Fatalf("underlying GetConfig called %d times for /other/app/private_key, want 1 (unaffected by the prefix eviction)", got)
+	}
+}
@@ -2,7 +2,8 @@ package util
 
 import (
 	configmgr "IEdgeInsights/libs/ConfigManager"
-	"log"
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"strconv"
@@ -12,6 +13,26 @@ import (
 	"github.com/golang/glog"
 )
 
+// ErrConfigMissing wraps a failure to read a key (private key, public key,
+// etcd-backed config) that GetMessageBusConfig needs to build its result.
+var ErrConfigMissing = errors.New("util: config missing")
+
+// ErrUnsupportedTransport wraps an unrecognized message bus type or topic
+// type passed to GetMessageBusConfig.
+var ErrUnsupportedTransport = errors.New("util: unsupported message bus transport")
+
+// configCache fronts every cfgMgrCli.GetConfig call GetMessageBusConfig
+// makes (public keys, private keys) with a DefaultConfigCacheTTL cache, so
+// startup and reconnects don't reissue the same etcd reads.
+var configCache = NewConfigCache(DefaultConfigCacheTTL)
+
+// SharedConfigCache returns the ConfigCache backing GetMessageBusConfig's
+// etcd reads, so callers can tune InvalidateKey calls (e.g. from
+// acl.Manager's Reload) or inspect Stats().
+func SharedConfigCache() *ConfigCache {
+	return configCache
+}
+
 // CheckPortAvailability - checks for port availability on hostname
 func CheckPortAvailability(hostname, port string) bool {
 	maxRetries := 1000
@@ -34,8 +55,22 @@ func CheckPortAvailability(hostname, port string) bool {
 }
 
 // GetMessageBusConfig - constrcuts config object based on topic type(pub/sub),
-// message bus type(tcp/ipc) and dev/prod mode
-func GetMessageBusConfig(topic string, topicType string, devMode bool, cfgMgrConfig map[string]string) map[string]interface{} {
+// message bus type(zmq_tcp/zmq_ipc/grpc_mtls/mqtt) and dev/prod mode. The
+// returned map is discriminated on "type"; a messagebus.Transport for
+// grpc_mtls/mqtt can be built straight from it via messagebus.New.
+//
+// Every failure path returns (nil, error) instead of killing the process,
+// wrapping either ErrConfigMissing or ErrUnsupportedTransport so callers
+// embedding this package can recover from a transient etcd blip instead of
+// going down with it.
+//
+// The "allowed_clients" entry this builds from the "Clients" env var
+// authorizes every topic in the app identically. Callers that need
+// per-topic authorization (and the ability to add/remove a subscriber
+// without a container restart) should call GetMessageBusConfigWithACL
+// instead, which overwrites this entry with acl.Manager.AllowedClients'
+// result.
+func GetMessageBusConfig(topic string, topicType string, devMode bool, cfgMgrConfig map[string]string) (map[string]interface{}, error) {
 	var subTopics []string
 	var topicConfigList []string
 	appName := os.Getenv("AppName")
@@ -43,30 +78,41 @@ func GetMessageBusConfig(topic string, topicType string, devMode bool, cfgMgrCon
 	topic = strings.TrimSpace(topic)
 	if strings.ToLower(topicType) == "sub" {
 		subTopics = strings.Split(topic, "/")
+		if len(subTopics) < 2 {
+			return nil, fmt.Errorf("%w: sub topic %q missing \"/\" separator", ErrUnsupportedTransport, topic)
+		}
 		topic = subTopics[1]
 	}
 
+	var envVar string
 	if topicType == "server" || topicType == "client" {
-		topicConfigList = strings.Split(os.Getenv("Server"), ",")
+		envVar = "Server"
 	} else {
-		topicConfigList = strings.Split(os.Getenv(topic+"_cfg"), ",")
+		envVar = topic + "_cfg"
+	}
+	topicConfigList = strings.Split(os.Getenv(envVar), ",")
+	if len(topicConfigList) < 2 {
+		return nil, fmt.Errorf("%w: env var %q: expected \"<type>,<address>\", got %q", ErrUnsupportedTransport, envVar, os.Getenv(envVar))
 	}
 	var messageBusConfig map[string]interface{}
 	topicConfigList[0] = strings.TrimSpace(topicConfigList[0])
 	topicConfigList[1] = strings.TrimSpace(topicConfigList[1])
 	if topicConfigList[0] == "zmq_tcp" {
 		address := strings.Split(topicConfigList[1], ":")
+		if len(address) < 2 {
+			return nil, fmt.Errorf("%w: address %q: expected \"<host>:<port>\"", ErrUnsupportedTransport, topicConfigList[1])
+		}
 		hostname := address[0]
 		port, err := strconv.ParseInt(address[1], 10, 64)
 		if err != nil {
-			glog.Errorf("string to int64 converstion Error: %v", err)
-			os.Exit(1)
+			return nil, fmt.Errorf("%w: port %q: %v", ErrUnsupportedTransport, address[1], err)
 		}
 		hostConfig := map[string]interface{}{
 			"host": hostname,
 			"port": port,
 		}
-		if strings.ToLower(topicType) == "pub" {
+		switch strings.ToLower(topicType) {
+		case "pub":
 			messageBusConfig = map[string]interface{}{
 				"type":            "zmq_tcp",
 				"zmq_tcp_publish": hostConfig,
@@ -77,47 +123,43 @@ func GetMessageBusConfig(topic string, topicType string, devMode bool, cfgMgrCon
 				subscribers := strings.Split(os.Getenv("Clients"), ",")
 				for _, subscriber := range subscribers {
 					subscriber = strings.TrimSpace(subscriber)
-					clientPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + subscriber)
+					clientPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+subscriber)
 					if err != nil {
-						glog.Errorf("Etcd GetConfig Error %v", err)
-						os.Exit(1)
+						return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, subscriber, err)
 					}
 					allowedClients = append(allowedClients, clientPublicKey)
 				}
-				serverSecretKey, err := cfgMgrCli.GetConfig("/" + appName + "/private_key")
+				serverSecretKey, err := configCache.Get(cfgMgrCli, "/"+appName+"/private_key")
 				if err != nil {
-					log.Fatal(err)
+					return nil, fmt.Errorf("%w: private key for %s: %v", ErrConfigMissing, appName, err)
 				}
 				messageBusConfig["allowed_clients"] = allowedClients
 				hostConfig["server_secret_key"] = serverSecretKey
 			}
-		} else if strings.ToLower(topicType) == "sub" {
+		case "sub":
 			messageBusConfig = map[string]interface{}{
 				"type": "zmq_tcp",
 				topic:  hostConfig,
 			}
 			if !devMode {
 				subTopics[0] = strings.TrimSpace(subTopics[0])
-				serverPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + subTopics[0])
+				serverPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+subTopics[0])
 				if err != nil {
-					glog.Errorf("Etcd GetConfig Error %v", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, subTopics[0], err)
 				}
-				clientSecretKey, err := cfgMgrCli.GetConfig("/" + appName + "/private_key")
+				clientSecretKey, err := configCache.Get(cfgMgrCli, "/"+appName+"/private_key")
 				if err != nil {
-					glog.Errorf("Etcd GetConfig Error %v", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("%w: private key for %s: %v", ErrConfigMissing, appName, err)
 				}
-				clientPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + appName)
+				clientPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+appName)
 				if err != nil {
-					glog.Errorf("Etcd GetConfig Error %v", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, appName, err)
 				}
 				hostConfig["server_public_key"] = serverPublicKey
 				hostConfig["client_secret_key"] = clientSecretKey
 				hostConfig["client_public_key"] = clientPublicKey
 			}
-		} else if strings.ToLower(topicType) == "server" {
+		case "server":
 			messageBusConfig = map[string]interface{}{
 				"type": "zmq_tcp",
 				topic:  hostConfig,
@@ -127,62 +169,106 @@ func GetMessageBusConfig(topic string, topicType string, devMode bool, cfgMgrCon
 				clients := strings.Split(os.Getenv("Clients"), ",")
 				for _, client := range clients {
 					client = strings.TrimSpace(client)
-					clientPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + client)
+					clientPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+client)
 					if err != nil {
-						glog.Errorf("Etcd GetConfig Error %v", err)
-						os.Exit(1)
+						return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, client, err)
 					}
 					allowedClients = append(allowedClients, clientPublicKey)
 				}
-				serverSecretKey, err := cfgMgrCli.GetConfig("/" + appName + "/private_key")
+				serverSecretKey, err := configCache.Get(cfgMgrCli, "/"+appName+"/private_key")
 				if err != nil {
-					log.Fatal(err)
+					return nil, fmt.Errorf("%w: private key for %s: %v", ErrConfigMissing, appName, err)
 				}
 				messageBusConfig["allowed_clients"] = allowedClients
 				hostConfig["server_secret_key"] = serverSecretKey
 			}
-		} else if strings.ToLower(topicType) == "client" {
+		case "client":
 			messageBusConfig = map[string]interface{}{
 				"type": "zmq_tcp",
 				topic:  hostConfig,
 			}
 			if !devMode {
-				clientPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + appName)
+				clientPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+appName)
 				if err != nil {
-					glog.Errorf("Etcd GetConfig Error %v", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, appName, err)
 				}
 
-				clientSecretKey, err := cfgMgrCli.GetConfig("/" + appName + "/private_key")
+				clientSecretKey, err := configCache.Get(cfgMgrCli, "/"+appName+"/private_key")
 				if err != nil {
-					log.Fatal(err)
+					return nil, fmt.Errorf("%w: private key for %s: %v", ErrConfigMissing, appName, err)
 				}
 
-				serverPublicKey, err := cfgMgrCli.GetConfig("/Publickeys/" + topic)
+				serverPublicKey, err := configCache.Get(cfgMgrCli, "/Publickeys/"+topic)
 				if err != nil {
-					glog.Errorf("Etcd GetConfig Error %v", err)
-					os.Exit(1)
+					return nil, fmt.Errorf("%w: public key for %s: %v", ErrConfigMissing, topic, err)
 				}
 
 				hostConfig["server_public_key"] = serverPublicKey
 				hostConfig["client_secret_key"] = clientSecretKey
 				hostConfig["client_public_key"] = clientPublicKey
 			}
-		} else {
-			panic("Unsupported Topic Type!!!")
+		default:
+			return nil, fmt.Errorf("%w: topic type %q", ErrUnsupportedTransport, topicType)
 		}
 	} else if topicConfigList[0] == "zmq_ipc" {
 		messageBusConfig = map[string]interface{}{
 			"type":       "zmq_ipc",
 			"socket_dir": topicConfigList[1],
 		}
+	} else if topicConfigList[0] == "grpc_mtls" {
+		address := strings.Split(topicConfigList[1], ":")
+		if len(address) < 2 {
+			return nil, fmt.Errorf("%w: address %q: expected \"<host>:<port>\"", ErrUnsupportedTransport, topicConfigList[1])
+		}
+		hostname := address[0]
+		port, err := strconv.ParseInt(address[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: port %q: %v", ErrUnsupportedTransport, address[1], err)
+		}
+		hostConfig := map[string]interface{}{
+			"host": hostname,
+			"port": port,
+		}
+		messageBusConfig = map[string]interface{}{
+			"type": "grpc_mtls",
+			topic:  hostConfig,
+		}
+		if !devMode {
+			// Reuse the same ConfigManager namespace CurveZMQ keys live
+			// under, so deployments don't need a second cert distribution
+			// mechanism just for gRPC.
+			serverCert, err := configCache.Get(cfgMgrCli, "/Publickeys/"+appName)
+			if err != nil {
+				return nil, fmt.Errorf("%w: cert for %s: %v", ErrConfigMissing, appName, err)
+			}
+			serverKey, err := configCache.Get(cfgMgrCli, "/"+appName+"/private_key")
+			if err != nil {
+				return nil, fmt.Errorf("%w: private key for %s: %v", ErrConfigMissing, appName, err)
+			}
+			// The CA that signed every app's server_cert, so grpcmtls can
+			// both verify the peer it dials and require+verify the client
+			// cert on the accept side - without it the handshake is only
+			// encrypted, never mutually authenticated.
+			caCert, err := configCache.Get(cfgMgrCli, "/CA/cert")
+			if err != nil {
+				return nil, fmt.Errorf("%w: CA cert: %v", ErrConfigMissing, err)
+			}
+			hostConfig["server_cert"] = serverCert
+			hostConfig["server_key"] = serverKey
+			hostConfig["ca_cert"] = caCert
+		}
+	} else if topicConfigList[0] == "mqtt" {
+		messageBusConfig = map[string]interface{}{
+			"type":       "mqtt",
+			"broker_url": topicConfigList[1],
+		}
 	} else {
-		panic("Unsupported MessageBus Type!!!")
+		return nil, fmt.Errorf("%w: message bus type %q", ErrUnsupportedTransport, topicConfigList[0])
 	}
-	return messageBusConfig
+	return messageBusConfig, nil
 }
 
-//GetTopics - returns list of topics based on topic type
+// GetTopics - returns list of topics based on topic type
 func GetTopics(topicType string) []string {
 	var topics []string
 	if strings.ToLower(topicType) == "pub" {
@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package acl resolves per-topic CurveZMQ allow-lists from a Policy stored
+// in etcd at /<AppName>/acl, instead of util.GetMessageBusConfig treating
+// the single "Clients" env var as the authorization list for every
+// publisher/server topic in the app. util.GetMessageBusConfigWithACL is the
+// caller that looks a topic's allowed public keys up via AllowedClients and
+// overwrites "allowed_clients" with them in place of the "Clients" env var
+// loop, so adding or removing a subscriber no longer needs a full container
+// restart - Reload pushes the updated allow-list out as soon as the etcd
+// key changes.
+package acl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/golang/glog"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const publicKeyPrefix = "/Publickeys/"
+
+// Manager resolves Policy documents and public keys for a single app
+// against etcd directly, since ConfigManager.GetConfig has no notion of a
+// prefix listing or a watch.
+type Manager struct {
+	appName    string
+	etcdClient *clientv3.Client
+	invalidate func(prefix string)
+}
+
+// Option configures optional Manager behavior.
+type Option func(*Manager)
+
+// WithCacheInvalidation registers a hook Reload calls with the public key
+// prefix whenever the policy changes, so a cache fronting public key
+// lookups (e.g. util.GetMessageBusConfig's util.ConfigCache) can punch a
+// hole instead of serving stale keys until its TTL expires. This is a
+// callback rather than a direct dependency on util.ConfigCache because
+// util.GetMessageBusConfigWithACL needs to import this package to resolve
+// per-topic allow-lists, and this package importing util back would be a
+// cycle.
+func WithCacheInvalidation(invalidate func(prefix string)) Option {
+	return func(m *Manager) { m.invalidate = invalidate }
+}
+
+// New constructs a Manager for appName, reading/watching etcd through
+// etcdClient.
+func New(appName string, etcdClient *clientv3.Client, opts ...Option) *Manager {
+	m := &Manager{appName: appName, etcdClient: etcdClient}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *Manager) policyKey() string {
+	return "/" + m.appName + "/acl"
+}
+
+// Load fetches and parses the current Policy from etcd.
+func (m *Manager) Load(ctx context.Context) (*Policy, error) {
+	resp, err := m.etcdClient.Get(ctx, m.policyKey())
+	if err != nil {
+		return nil, fmt.Errorf("acl: read policy %s: %w", m.policyKey(), err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("acl: policy %s not found", m.policyKey())
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(resp.Kvs[0].Value, &policy); err != nil {
+		return nil, fmt.Errorf("acl: parse policy %s: %w", m.policyKey(), err)
+	}
+	return &policy, nil
+}
+
+// Role identifies which side of a topic a caller is resolving allowed
+// clients for.
+type Role int
+
+const (
+	// RolePublisher resolves the topic's allowed_subscribers/allowed_clients.
+	RolePublisher Role = iota
+	// RoleSubscriber resolves the topic's allowed_publishers.
+	RoleSubscriber
+	// RoleServer resolves the topic's allowed_clients.
+	RoleServer
+	// RoleClient resolves the topic's allowed_publishers (the server it may call).
+	RoleClient
+)
+
+// AllowedClients resolves the CurveZMQ public keys for every name or glob
+// pattern policy allows for topic/role, the same shape
+// util.GetMessageBusConfig's "allowed_clients" entry expects. It returns nil
+// when policy has no allow-list for topic/role, and an empty, non-nil slice
+// when the allow-list matches no key under /Publickeys/.
+func (m *Manager) AllowedClients(ctx context.Context, policy *Policy, topic string, role Role) ([]string, error) {
+	topicPolicy, ok := policy.Topics[topic]
+	if !ok {
+		return nil, nil
+	}
+
+	var patterns []string
+	switch role {
+	case RolePublisher:
+		patterns = topicPolicy.AllowedSubscribers
+		if len(patterns) == 0 {
+			patterns = topicPolicy.AllowedClients
+		}
+	case RoleServer:
+		patterns = topicPolicy.AllowedClients
+	case RoleSubscriber, RoleClient:
+		patterns = topicPolicy.AllowedPublishers
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	resp, err := m.etcdClient.Get(ctx, publicKeyPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("acl: list %s: %w", publicKeyPrefix, err)
+	}
+
+	publicKeys := []string{}
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		for _, kv := range resp.Kvs {
+			name := strings.TrimPrefix(string(kv.Key), publicKeyPrefix)
+			matched, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("acl: bad glob %q: %w", pattern, err)
+			}
+			if matched && !seen[name] {
+				seen[name] = true
+				publicKeys = append(publicKeys, string(kv.Value))
+			}
+		}
+	}
+	return publicKeys, nil
+}
+
+// Reload watches the policy key and emits a freshly loaded Policy every
+// time it changes, until ctx is canceled (the returned channel is then
+// closed). This is what lets a long-running publisher/server rotate its
+// allowed-client set without restarting.
+func (m *Manager) Reload(ctx context.Context) <-chan *Policy {
+	out := make(chan *Policy, 1)
+	watchChan := m.etcdClient.Watch(ctx, m.policyKey())
+
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp, ok := <-watchChan:
+				if !ok {
+					return
+				}
+				if err := resp.Err(); err != nil {
+					glog.Errorf("acl: watch %s: %v", m.policyKey(), err)
+					continue
+				}
+				policy, err := m.Load(ctx)
+				if err != nil {
+					glog.Errorf("acl: reload %s: %v", m.policyKey(), err)
+					continue
+				}
+				// The rotated policy can add or drop allowed names, so the
+				// public keys GetMessageBusConfig cached for the old
+				// allow-list may now be stale or missing entirely.
+				if m.invalidate != nil {
+					m.invalidate(publicKeyPrefix)
+				}
+				select {
+				case out <- policy:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
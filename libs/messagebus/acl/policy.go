@@ -0,0 +1,26 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package acl
+
+// Policy is the per-topic authorization document stored at /<AppName>/acl.
+// It replaces treating the single "Clients" env var as the authorization
+// list for every topic an app publishes/serves.
+type Policy struct {
+	Topics map[string]TopicPolicy `json:"topics"`
+}
+
+// TopicPolicy is the allow-list for one topic. Each entry is either a
+// literal client/app name or a path.Match glob (e.g. "cam-*").
+type TopicPolicy struct {
+	AllowedPublishers  []string `json:"allowed_publishers,omitempty"`
+	AllowedSubscribers []string `json:"allowed_subscribers,omitempty"`
+	AllowedClients     []string `json:"allowed_clients,omitempty"`
+}
@@ -0,0 +1,37 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package grpcmtls
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+)
+
+// client is the gRPC client side of the unary Request RPC.
+type client struct {
+	conn *grpc.ClientConn
+}
+
+// Request implements messagebus.Client.
+func (c *client) Request(payload []byte, metadata map[string]string) ([]byte, map[string]string, error) {
+	req := &wireFrame{Payload: payload, Metadata: metadata}
+	resp := &wireFrame{}
+	if err := c.conn.Invoke(context.Background(), requestMethodName, req, resp); err != nil {
+		return nil, nil, err
+	}
+	return resp.Payload, resp.Metadata, nil
+}
+
+// Close implements messagebus.Client.
+func (c *client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package grpcmtls
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// publisher is the gRPC server side of the Stream RPC: every connected
+// Subscriber gets its own fan-out channel, fed by Publish.
+type publisher struct {
+	topic  string
+	server *grpc.Server
+
+	mu          sync.Mutex
+	subscribers map[chan frame]struct{}
+}
+
+// pubSubServer is the HandlerType grpc.Server.RegisterService type-checks
+// ss against (it does reflect.TypeOf(sd.HandlerType).Elem() unconditionally
+// whenever ss is non-nil, so a literal nil HandlerType panics on every
+// RegisterService call). *publisher implements it via streamHandler.
+type pubSubServer interface {
+	streamHandler(interface{}, grpc.ServerStream) error
+}
+
+func (p *publisher) serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: streamServiceName,
+		HandlerType: (*pubSubServer)(nil),
+		Streams: []grpc.StreamDesc{
+			{
+				StreamName:    "Stream",
+				Handler:       p.streamHandler,
+				ServerStreams: true,
+			},
+		},
+		Metadata: "grpcmtls",
+	}
+}
+
+func (p *publisher) streamHandler(_ interface{}, stream grpc.ServerStream) error {
+	ch := make(chan frame, 64)
+	p.mu.Lock()
+	p.subscribers[ch] = struct{}{}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.subscribers, ch)
+		p.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case f := <-ch:
+			if err := stream.SendMsg(&wireFrame{Payload: f.payload, Metadata: f.metadata}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
+// Publish implements messagebus.Publisher. A subscriber whose fan-out
+// channel is full is dropped for that message rather than blocking the
+// publisher, matching the best-effort delivery semantics of the ZeroMQ pub
+// socket.
+func (p *publisher) Publish(payload []byte, metadata map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subscribers {
+		select {
+		case ch <- frame{payload: payload, metadata: metadata}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close implements messagebus.Publisher.
+func (p *publisher) Close() error {
+	p.server.GracefulStop()
+	return nil
+}
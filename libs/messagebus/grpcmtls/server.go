@@ -0,0 +1,77 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package grpcmtls
+
+import (
+	"context"
+	"net"
+
+	"IEdgeInsights/libs/messagebus"
+
+	"google.golang.org/grpc"
+)
+
+// server is the gRPC server side of the unary Request RPC.
+type server struct {
+	listener   net.Listener
+	grpcServer *grpc.Server
+}
+
+// reqRespServer is the HandlerType grpc.Server.RegisterService type-checks
+// ss against (it does reflect.TypeOf(sd.HandlerType).Elem() unconditionally
+// whenever ss is non-nil, so a literal nil HandlerType panics on every
+// RegisterService call). *server implements it via requestHandler.
+type reqRespServer interface {
+	requestHandler(messagebus.Handler) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error)
+}
+
+func (s *server) serviceDesc(handler messagebus.Handler) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: requestServiceName,
+		HandlerType: (*reqRespServer)(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Request",
+				Handler:    s.requestHandler(handler),
+			},
+		},
+		Metadata: "grpcmtls",
+	}
+}
+
+func (s *server) requestHandler(handler messagebus.Handler) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := &wireFrame{}
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		respPayload, respMeta, err := handler(req.Payload, req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		return &wireFrame{Payload: respPayload, Metadata: respMeta}, nil
+	}
+}
+
+// Serve implements messagebus.Server. It blocks until Close is called.
+func (s *server) Serve(handler messagebus.Handler) error {
+	s.grpcServer = grpc.NewServer(grpc.ForceServerCodec(rawCodec{}))
+	s.grpcServer.RegisterService(s.serviceDesc(handler), s)
+	return s.grpcServer.Serve(s.listener)
+}
+
+// Close implements messagebus.Server.
+func (s *server) Close() error {
+	if s.grpcServer != nil {
+		s.grpcServer.GracefulStop()
+	}
+	return s.listener.Close()
+}
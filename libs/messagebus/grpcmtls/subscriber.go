@@ -0,0 +1,33 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package grpcmtls
+
+import "google.golang.org/grpc"
+
+// subscriber is the gRPC client side of the Stream RPC.
+type subscriber struct {
+	conn   *grpc.ClientConn
+	stream grpc.ClientStream
+}
+
+// Receive implements messagebus.Subscriber.
+func (s *subscriber) Receive() ([]byte, map[string]string, error) {
+	var msg wireFrame
+	if err := s.stream.RecvMsg(&msg); err != nil {
+		return nil, nil, err
+	}
+	return msg.Payload, msg.Metadata, nil
+}
+
+// Close implements messagebus.Subscriber.
+func (s *subscriber) Close() error {
+	return s.conn.Close()
+}
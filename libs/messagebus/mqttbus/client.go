@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package mqttbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// client issues request/response RPCs over MQTT by publishing to the
+// server's request topic with a response-topic and correlation-data
+// property, then waiting on replyTopic for the matching correlation ID -
+// the standard MQTT v5 request/response pattern.
+type client struct {
+	client     *paho.Client
+	replyTopic string
+
+	mu      sync.Mutex
+	pending map[string]chan *paho.Publish
+}
+
+func (c *client) deliver(pub *paho.Publish) {
+	if pub.Properties == nil || len(pub.Properties.CorrelationData) == 0 {
+		return
+	}
+	correlationID := string(pub.Properties.CorrelationData)
+
+	c.mu.Lock()
+	ch, ok := c.pending[correlationID]
+	if ok {
+		delete(c.pending, correlationID)
+	}
+	c.mu.Unlock()
+
+	if ok {
+		ch <- pub
+	}
+}
+
+// Request implements messagebus.Client. requestTopic is the server's
+// well-known topic, conventionally "reqresp/request".
+func (c *client) Request(payload []byte, metadata map[string]string) ([]byte, map[string]string, error) {
+	correlationID, err := randomID()
+	if err != nil {
+		return nil, nil, fmt.Errorf("mqttbus: generate correlation id: %w", err)
+	}
+
+	reply := make(chan *paho.Publish, 1)
+	c.mu.Lock()
+	c.pending[correlationID] = reply
+	c.mu.Unlock()
+
+	_, err = c.client.Publish(context.Background(), &paho.Publish{
+		Topic:   "reqresp/request",
+		QoS:     qos,
+		Payload: payload,
+		Properties: &paho.PublishProperties{
+			User:            *metadataProps(metadata),
+			ResponseTopic:   c.replyTopic,
+			CorrelationData: []byte(correlationID),
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("mqttbus: request: %w", err)
+	}
+
+	pub := <-reply
+	var respMetadata map[string]string
+	if pub.Properties != nil {
+		respMetadata = metadataFromProps(&pub.Properties.User)
+	}
+	return pub.Payload, respMetadata, nil
+}
+
+// Close implements messagebus.Client.
+func (c *client) Close() error {
+	_, err := c.client.Unsubscribe(context.Background(), &paho.Unsubscribe{Topics: []string{c.replyTopic}})
+	if err != nil {
+		return fmt.Errorf("mqttbus: unsubscribe reply topic: %w", err)
+	}
+	return nil
+}
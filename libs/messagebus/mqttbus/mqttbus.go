@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package mqttbus implements messagebus.Transport over MQTT v5, for interop
+// with existing broker deployments. It registers itself under the "mqtt"
+// config type. Request/response (client/server) is layered on top of
+// pub/sub using the MQTT v5 response-topic and correlation-data properties,
+// since MQTT itself has no RPC primitive.
+package mqttbus
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+
+	"IEdgeInsights/libs/messagebus"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+func init() {
+	messagebus.Register("mqtt", newTransport)
+}
+
+const qos = 1
+
+// transport is a messagebus.Transport over one MQTT v5 connection to the
+// broker named in the "broker_url" config key.
+type transport struct {
+	client *paho.Client
+}
+
+func newTransport(config map[string]interface{}) (messagebus.Transport, error) {
+	brokerURL, _ := config["broker_url"].(string)
+	if brokerURL == "" {
+		return nil, fmt.Errorf("mqttbus: config missing \"broker_url\"")
+	}
+
+	conn, err := net.Dial("tcp", brokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("mqttbus: dial %s: %w", brokerURL, err)
+	}
+
+	client := paho.NewClient(paho.ClientConfig{Conn: conn})
+	clientID, err := randomID()
+	if err != nil {
+		return nil, fmt.Errorf("mqttbus: generate client id: %w", err)
+	}
+	if _, err := client.Connect(context.Background(), &paho.Connect{
+		ClientID:   clientID,
+		CleanStart: true,
+		KeepAlive:  30,
+	}); err != nil {
+		return nil, fmt.Errorf("mqttbus: connect %s: %w", brokerURL, err)
+	}
+
+	return &transport{client: client}, nil
+}
+
+func randomID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// NewPublisher implements messagebus.Transport.
+func (t *transport) NewPublisher(topic string) (messagebus.Publisher, error) {
+	return &publisher{client: t.client, topic: topic}, nil
+}
+
+// NewSubscriber implements messagebus.Transport.
+func (t *transport) NewSubscriber(topic string) (messagebus.Subscriber, error) {
+	sub := &subscriber{client: t.client, topic: topic, messages: make(chan *paho.Publish, 64), done: make(chan struct{})}
+	t.client.Router.RegisterHandler(topic, sub.enqueue)
+	if _, err := t.client.Subscribe(context.Background(), &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: topic, QoS: qos}},
+	}); err != nil {
+		return nil, fmt.Errorf("mqttbus: subscribe %s: %w", topic, err)
+	}
+	return sub, nil
+}
+
+// NewClient implements messagebus.Transport.
+func (t *transport) NewClient() (messagebus.Client, error) {
+	replyTopic := "reqresp/" + mustRandomID() + "/response"
+	c := &client{client: t.client, replyTopic: replyTopic, pending: make(map[string]chan *paho.Publish)}
+	t.client.Router.RegisterHandler(replyTopic, c.deliver)
+	if _, err := t.client.Subscribe(context.Background(), &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: replyTopic, QoS: qos}},
+	}); err != nil {
+		return nil, fmt.Errorf("mqttbus: subscribe reply topic: %w", err)
+	}
+	return c, nil
+}
+
+// NewServer implements messagebus.Transport.
+func (t *transport) NewServer() (messagebus.Server, error) {
+	return &server{client: t.client, requests: make(chan *paho.Publish, 64), done: make(chan struct{})}, nil
+}
+
+func mustRandomID() string {
+	id, err := randomID()
+	if err != nil {
+		// crypto/rand only fails if the OS entropy source is broken, in
+		// which case nothing downstream can be trusted either.
+		panic(err)
+	}
+	return id
+}
+
+func metadataProps(metadata map[string]string) *paho.UserProperties {
+	props := &paho.UserProperties{}
+	for k, v := range metadata {
+		props.Add(k, v)
+	}
+	return props
+}
+
+func metadataFromProps(props *paho.UserProperties) map[string]string {
+	metadata := make(map[string]string, len(*props))
+	for _, prop := range *props {
+		metadata[prop.Key] = prop.Value
+	}
+	return metadata
+}
@@ -0,0 +1,46 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package mqttbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// publisher publishes to a fixed topic on the shared transport connection.
+type publisher struct {
+	client *paho.Client
+	topic  string
+}
+
+// Publish implements messagebus.Publisher.
+func (p *publisher) Publish(payload []byte, metadata map[string]string) error {
+	_, err := p.client.Publish(context.Background(), &paho.Publish{
+		Topic:      p.topic,
+		QoS:        qos,
+		Payload:    payload,
+		Properties: &paho.PublishProperties{User: *metadataProps(metadata)},
+	})
+	if err != nil {
+		return fmt.Errorf("mqttbus: publish %s: %w", p.topic, err)
+	}
+	return nil
+}
+
+// Close implements messagebus.Publisher. The underlying connection is
+// shared by every Publisher/Subscriber/Client/Server built from the same
+// Transport, so Close is a no-op here - the caller disconnects by
+// discarding the Transport.
+func (p *publisher) Close() error {
+	return nil
+}
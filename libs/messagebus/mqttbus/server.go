@@ -0,0 +1,93 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package mqttbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"IEdgeInsights/libs/messagebus"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+const requestTopic = "reqresp/request"
+
+// server answers client.Request calls published to requestTopic, replying
+// to whatever response-topic/correlation-data the client attached.
+//
+// done signals Close instead of closing requests outright: the router
+// keeps calling the handler from its own goroutine until Unsubscribe
+// actually takes effect, and a bare close(requests) would race that
+// goroutine's send and panic.
+type server struct {
+	client    *paho.Client
+	requests  chan *paho.Publish
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *server) enqueue(pub *paho.Publish) {
+	select {
+	case s.requests <- pub:
+	case <-s.done:
+	}
+}
+
+// Serve implements messagebus.Server. It blocks until Close is called.
+func (s *server) Serve(handler messagebus.Handler) error {
+	s.client.Router.RegisterHandler(requestTopic, s.enqueue)
+	if _, err := s.client.Subscribe(context.Background(), &paho.Subscribe{
+		Subscriptions: []paho.SubscribeOptions{{Topic: requestTopic, QoS: qos}},
+	}); err != nil {
+		return fmt.Errorf("mqttbus: subscribe %s: %w", requestTopic, err)
+	}
+
+	for {
+		var pub *paho.Publish
+		select {
+		case pub = <-s.requests:
+		case <-s.done:
+			return nil
+		}
+
+		var metadata map[string]string
+		if pub.Properties != nil {
+			metadata = metadataFromProps(&pub.Properties.User)
+		}
+		respPayload, respMetadata, err := handler(pub.Payload, metadata)
+		if err != nil || pub.Properties == nil || pub.Properties.ResponseTopic == "" {
+			continue
+		}
+		if _, err := s.client.Publish(context.Background(), &paho.Publish{
+			Topic:   pub.Properties.ResponseTopic,
+			QoS:     qos,
+			Payload: respPayload,
+			Properties: &paho.PublishProperties{
+				User:            *metadataProps(respMetadata),
+				CorrelationData: pub.Properties.CorrelationData,
+			},
+		}); err != nil {
+			return fmt.Errorf("mqttbus: reply to %s: %w", pub.Properties.ResponseTopic, err)
+		}
+	}
+}
+
+// Close implements messagebus.Server.
+func (s *server) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	_, err := s.client.Unsubscribe(context.Background(), &paho.Unsubscribe{Topics: []string{requestTopic}})
+	if err != nil {
+		return fmt.Errorf("mqttbus: unsubscribe %s: %w", requestTopic, err)
+	}
+	return nil
+}
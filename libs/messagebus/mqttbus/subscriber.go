@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package mqttbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/eclipse/paho.golang/paho"
+)
+
+// subscriber buffers incoming publishes for topic so Receive can be called
+// synchronously, even though the underlying paho router dispatches them
+// from its own read loop goroutine.
+//
+// done signals Close instead of closing messages outright: the router
+// keeps calling enqueue from its own goroutine until Unsubscribe actually
+// takes effect, and a bare close(messages) would race that goroutine's
+// send and panic.
+type subscriber struct {
+	client    *paho.Client
+	topic     string
+	messages  chan *paho.Publish
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *subscriber) enqueue(pub *paho.Publish) {
+	select {
+	case s.messages <- pub:
+	case <-s.done:
+	default:
+		// Slow consumer: drop rather than block the router's read loop.
+	}
+}
+
+// Receive implements messagebus.Subscriber.
+func (s *subscriber) Receive() ([]byte, map[string]string, error) {
+	select {
+	case pub := <-s.messages:
+		var metadata map[string]string
+		if pub.Properties != nil {
+			metadata = metadataFromProps(&pub.Properties.User)
+		}
+		return pub.Payload, metadata, nil
+	case <-s.done:
+		return nil, nil, fmt.Errorf("mqttbus: subscriber closed")
+	}
+}
+
+// Close implements messagebus.Subscriber.
+func (s *subscriber) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	_, err := s.client.Unsubscribe(context.Background(), &paho.Unsubscribe{Topics: []string{s.topic}})
+	if err != nil {
+		return fmt.Errorf("mqttbus: unsubscribe %s: %w", s.topic, err)
+	}
+	return nil
+}
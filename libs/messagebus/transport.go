@@ -0,0 +1,76 @@
+/*
+Copyright (c) 2018 Intel Corporation.
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package messagebus lets publishers/subscribers/clients/servers be built
+// against transports other than ZeroMQ, out of the discriminated config
+// util.GetMessageBusConfig returns. Concrete transports (grpcmtls, mqttbus)
+// register themselves via Register from an init() func, so New only needs
+// the type string to pick one - no compile-time dependency on every driver.
+package messagebus
+
+import "fmt"
+
+// Publisher sends framed messages for a topic to any connected Subscribers.
+type Publisher interface {
+	Publish(payload []byte, metadata map[string]string) error
+	Close() error
+}
+
+// Subscriber receives framed messages published for a topic.
+type Subscriber interface {
+	Receive() (payload []byte, metadata map[string]string, err error)
+	Close() error
+}
+
+// Client issues unary requests against a Server.
+type Client interface {
+	Request(payload []byte, metadata map[string]string) (respPayload []byte, respMetadata map[string]string, err error)
+	Close() error
+}
+
+// Handler answers a single Client request.
+type Handler func(payload []byte, metadata map[string]string) ([]byte, map[string]string, error)
+
+// Server answers unary requests from Clients via Handler.
+type Server interface {
+	Serve(handler Handler) error
+	Close() error
+}
+
+// Transport constructs publishers/subscribers/clients/servers for a single
+// message bus backend.
+type Transport interface {
+	NewPublisher(topic string) (Publisher, error)
+	NewSubscriber(topic string) (Subscriber, error)
+	NewClient() (Client, error)
+	NewServer() (Server, error)
+}
+
+// Factory constructs a Transport from a util.GetMessageBusConfig config map.
+type Factory func(config map[string]interface{}) (Transport, error)
+
+var factories = map[string]Factory{}
+
+// Register adds a Factory for the given config["type"] value. Driver
+// packages call this from an init() func.
+func Register(transportType string, factory Factory) {
+	factories[transportType] = factory
+}
+
+// New builds the Transport selected by config["type"], as returned by
+// util.GetMessageBusConfig.
+func New(config map[string]interface{}) (Transport, error) {
+	transportType, _ := config["type"].(string)
+	factory, ok := factories[transportType]
+	if !ok {
+		return nil, fmt.Errorf("messagebus: unsupported transport %q", transportType)
+	}
+	return factory(config)
+}